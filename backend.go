@@ -6,40 +6,55 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"schneider.vip/retryspool/storage/data/postgres/migrations"
+)
+
+// StorageMode selects how message payloads are physically stored.
+type StorageMode int
+
+const (
+	// ModeBytea stores payloads inline in a BYTEA column. Simple, but
+	// StoreData/GetDataReader/GetDataWriter must buffer the whole payload
+	// in memory because BYTEA values are read and written whole.
+	ModeBytea StorageMode = iota
+	// ModeLargeObject stores payloads via PostgreSQL's large-object
+	// facility (pg_largeobject), referenced from the table by OID. This
+	// allows StoreData/GetDataReader/GetDataWriter to stream data in fixed
+	// size chunks instead of holding the full payload in memory.
+	ModeLargeObject
 )
 
 // Backend implements PostgreSQL data storage
 type Backend struct {
-	db        *sql.DB
-	tableName string
+	db               *sql.DB
+	dsn              string
+	tableName        string
+	mode             StorageMode
+	maxBatchRowBytes int64
 }
 
-// createTable creates the data table if it doesn't exist
-func (b *Backend) createTable() error {
-	query := fmt.Sprintf(`
-	CREATE TABLE IF NOT EXISTS %s (
-		message_id VARCHAR(255) PRIMARY KEY,
-		data BYTEA NOT NULL,
-		size BIGINT NOT NULL,
-		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-		updated TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
-	);
-	
-	-- Create index for timestamp-based queries
-	CREATE INDEX IF NOT EXISTS idx_%s_created ON %s(created);
-	CREATE INDEX IF NOT EXISTS idx_%s_updated ON %s(updated);
-	`,
-		b.tableName,
-		b.tableName, b.tableName,
-		b.tableName, b.tableName,
-	)
-
-	_, err := b.db.Exec(query)
-	return err
+// migrationTemplateData builds the values the embedded schema migrations
+// need to render SQL for this backend's table and storage mode.
+func (b *Backend) migrationTemplateData() migrations.TemplateData {
+	data := migrations.TemplateData{
+		Table:      b.tableName,
+		DataColumn: "data BYTEA NOT NULL",
+		Bytea:      true,
+	}
+	if b.mode == ModeLargeObject {
+		data.DataColumn = "data OID NOT NULL"
+		data.Bytea = false
+	}
+	return data
 }
 
 // StoreData stores message data and returns the actual size written
 func (b *Backend) StoreData(ctx context.Context, messageID string, data io.Reader) (int64, error) {
+	if b.mode == ModeLargeObject {
+		return b.storeDataLO(ctx, messageID, data)
+	}
+
 	// Read all data into memory (PostgreSQL bytea limitation)
 	dataBytes, err := io.ReadAll(data)
 	if err != nil {
@@ -68,6 +83,10 @@ func (b *Backend) StoreData(ctx context.Context, messageID string, data io.Reade
 
 // GetDataReader returns a reader for message data
 func (b *Backend) GetDataReader(ctx context.Context, messageID string) (io.ReadCloser, error) {
+	if b.mode == ModeLargeObject {
+		return b.getDataReaderLO(ctx, messageID)
+	}
+
 	query := fmt.Sprintf(`SELECT data FROM %s WHERE message_id = $1`, b.tableName)
 	
 	var data []byte
@@ -105,6 +124,10 @@ func (brc *bytesReadCloser) Close() error {
 
 // GetDataWriter returns a writer for message data
 func (b *Backend) GetDataWriter(ctx context.Context, messageID string) (io.WriteCloser, error) {
+	if b.mode == ModeLargeObject {
+		return b.getDataWriterLO(ctx, messageID)
+	}
+
 	return &postgresDataWriter{
 		backend:   b,
 		messageID: messageID,
@@ -164,6 +187,10 @@ func (pdw *postgresDataWriter) Close() error {
 
 // DeleteData removes message data
 func (b *Backend) DeleteData(ctx context.Context, messageID string) error {
+	if b.mode == ModeLargeObject {
+		return b.deleteDataLO(ctx, messageID)
+	}
+
 	query := fmt.Sprintf(`DELETE FROM %s WHERE message_id = $1`, b.tableName)
 	
 	result, err := b.db.ExecContext(ctx, query, messageID)
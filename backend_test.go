@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -43,10 +44,18 @@ func setupTestDB(t *testing.T) *Backend {
 }
 
 func cleanupTestDB(t *testing.T, backend *Backend) {
-	// Clean up test data
-	_, err := backend.db.Exec("DROP TABLE IF EXISTS test_data")
-	if err != nil {
-		t.Logf("Failed to cleanup test table: %v", err)
+	// Clean up the table itself plus the artifacts Factory.Create installs
+	// alongside it (the migration tracking table, the notify trigger and
+	// its function), so repeated test runs start from a clean slate.
+	statements := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", backend.tableName),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s_schema_migrations", backend.tableName),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s_notify() CASCADE", backend.tableName),
+	}
+	for _, stmt := range statements {
+		if _, err := backend.db.Exec(stmt); err != nil {
+			t.Logf("Failed to clean up with %q: %v", stmt, err)
+		}
 	}
 	backend.Close()
 }
@@ -298,11 +307,25 @@ func TestBackend_UpdateData(t *testing.T) {
 
 func TestFactory_Create(t *testing.T) {
 	dsn := getTestDSN()
-	
+
+	// Check connectivity first so only a genuinely unavailable database
+	// skips the test; once we know Postgres is reachable, any error from
+	// Create (e.g. a bad migration or trigger statement) must fail the
+	// test rather than silently skip it.
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	db.Close()
+
 	factory := NewFactory(dsn).WithTableName("factory_test_data")
 	backend, err := factory.Create()
 	if err != nil {
-		t.Skipf("PostgreSQL not available: %v", err)
+		t.Fatalf("Failed to create backend: %v", err)
 	}
 	defer backend.Close()
 
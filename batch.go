@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StoreBatch ingests many messages in a single round trip using PostgreSQL's
+// COPY protocol, which is substantially faster than one INSERT per message
+// for workloads that enqueue many small messages at once. Rows are still
+// buffered per message (COPY reads whole rows), just not round-tripped one
+// statement at a time.
+//
+// iter is called repeatedly to pull the next (messageID, reader) pair; it
+// returns ok=false once exhausted. StoreBatch returns the number of rows
+// actually merged into the table.
+//
+// COPY cannot perform upserts directly, so messages are first copied into a
+// temporary table and then merged into the real table with a single
+// INSERT ... ON CONFLICT, all inside one transaction. If iter yields the
+// same messageID more than once, the last occurrence wins, matching
+// StoreData's upsert semantics.
+func (b *Backend) StoreBatch(ctx context.Context, iter func() (messageID string, r io.Reader, ok bool)) (int, error) {
+	if b.mode == ModeLargeObject {
+		return 0, fmt.Errorf("StoreBatch is not supported with ModeLargeObject; use StoreData instead")
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tempTable := fmt.Sprintf("%s_batch_tmp", b.tableName)
+	createTemp := fmt.Sprintf(`CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP`, tempTable, b.tableName)
+	if _, err := tx.ExecContext(ctx, createTemp); err != nil {
+		return 0, fmt.Errorf("failed to create batch temp table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(tempTable, "message_id", "data", "size", "created", "updated"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare COPY statement: %w", err)
+	}
+
+	for {
+		messageID, r, ok := iter()
+		if !ok {
+			break
+		}
+
+		dataBytes, err := io.ReadAll(r)
+		if err != nil {
+			stmt.Close()
+			return 0, fmt.Errorf("failed to read data for message %s: %w", messageID, err)
+		}
+
+		if b.maxBatchRowBytes > 0 && int64(len(dataBytes)) > b.maxBatchRowBytes {
+			stmt.Close()
+			return 0, fmt.Errorf("data for message %s is %d bytes, exceeds WithMaxBatchRowBytes limit of %d", messageID, len(dataBytes), b.maxBatchRowBytes)
+		}
+
+		now := time.Now()
+		if _, err := stmt.ExecContext(ctx, messageID, dataBytes, int64(len(dataBytes)), now, now); err != nil {
+			stmt.Close()
+			return 0, fmt.Errorf("failed to copy data for message %s: %w", messageID, err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, fmt.Errorf("failed to flush COPY: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close COPY statement: %w", err)
+	}
+
+	// DISTINCT ON collapses duplicate message IDs within the temp table
+	// before the upsert, keeping the last-copied row per ID (ctid DESC);
+	// otherwise ON CONFLICT DO UPDATE would try to touch the same target
+	// row twice and PostgreSQL would reject the whole statement.
+	mergeQuery := fmt.Sprintf(`
+		INSERT INTO %s (message_id, data, size, created, updated)
+		SELECT message_id, data, size, created, updated FROM (
+			SELECT DISTINCT ON (message_id) message_id, data, size, created, updated
+			FROM %s
+			ORDER BY message_id, ctid DESC
+		) deduped
+		ON CONFLICT (message_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			size = EXCLUDED.size,
+			updated = EXCLUDED.updated
+	`, b.tableName, tempTable)
+	result, err := tx.ExecContext(ctx, mergeQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge batch into %s: %w", b.tableName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return int(n), nil
+}
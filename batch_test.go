@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBackend_StoreBatch(t *testing.T) {
+	backend := setupTestDB(t)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	rows := []struct {
+		messageID string
+		data      string
+	}{
+		{"batch-message-1", "first message"},
+		{"batch-message-2", "second message"},
+		{"batch-message-3", "third message"},
+	}
+
+	i := 0
+	n, err := backend.StoreBatch(ctx, func() (string, io.Reader, bool) {
+		if i >= len(rows) {
+			return "", nil, false
+		}
+		row := rows[i]
+		i++
+		return row.messageID, strings.NewReader(row.data), true
+	})
+	if err != nil {
+		t.Fatalf("Failed to store batch: %v", err)
+	}
+	if n != len(rows) {
+		t.Errorf("Expected %d rows merged, got %d", len(rows), n)
+	}
+
+	for _, row := range rows {
+		reader, err := backend.GetDataReader(ctx, row.messageID)
+		if err != nil {
+			t.Fatalf("Failed to get data reader for %s: %v", row.messageID, err)
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("Failed to read data for %s: %v", row.messageID, err)
+		}
+		if string(got) != row.data {
+			t.Errorf("Data mismatch for %s: expected %s, got %s", row.messageID, row.data, string(got))
+		}
+	}
+}
+
+func TestBackend_StoreBatch_DuplicateMessageIDLastWriteWins(t *testing.T) {
+	backend := setupTestDB(t)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	values := []string{"first value", "second value", "final value"}
+
+	i := 0
+	n, err := backend.StoreBatch(ctx, func() (string, io.Reader, bool) {
+		if i >= len(values) {
+			return "", nil, false
+		}
+		v := values[i]
+		i++
+		return "batch-duplicate-message", strings.NewReader(v), true
+	})
+	if err != nil {
+		t.Fatalf("Failed to store batch with duplicate message IDs: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 row merged for a duplicated message ID, got %d", n)
+	}
+
+	reader, err := backend.GetDataReader(ctx, "batch-duplicate-message")
+	if err != nil {
+		t.Fatalf("Failed to get data reader: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	if string(got) != values[len(values)-1] {
+		t.Errorf("Expected last-write-wins value %q, got %q", values[len(values)-1], string(got))
+	}
+}
+
+func TestBackend_StoreBatch_MaxRowBytes(t *testing.T) {
+	dsn := getTestDSN()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	db.Close()
+
+	factory := NewFactory(dsn).WithTableName("batch_limit_test_data").WithMaxBatchRowBytes(4)
+	backendIface, err := factory.Create()
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	backend := backendIface.(*Backend)
+	defer cleanupTestDB(t, backend)
+
+	called := false
+	_, err = backend.StoreBatch(context.Background(), func() (string, io.Reader, bool) {
+		if called {
+			return "", nil, false
+		}
+		called = true
+		return "batch-oversized-message", strings.NewReader("too big"), true
+	})
+	if err == nil {
+		t.Fatal("Expected StoreBatch to reject a row exceeding WithMaxBatchRowBytes")
+	}
+}
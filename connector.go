@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// tlsDialer dials the raw TCP connection and, when tlsConfig is set,
+// performs the TLS handshake itself before handing the connection to
+// lib/pq. This is needed because lib/pq's own DSN-driven TLS handling
+// (sslmode/sslrootcert) can only load certificates from the filesystem,
+// with no way to supply an in-memory cert pool loaded from a secret
+// store. When tlsConfig is set, the DSN must also disable lib/pq's own
+// TLS (sslmode=disable) since the handshake has already happened here.
+type tlsDialer struct {
+	tlsConfig      *tls.Config
+	connectTimeout time.Duration
+}
+
+// Dial implements pq.Dialer, the interface pq.Connector.Dialer actually
+// requires (pq.DialerContext is not enough on its own).
+func (d *tlsDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialTimeout implements pq.Dialer.
+func (d *tlsDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+func (d *tlsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: d.connectTimeout}
+	rawConn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.tlsConfig == nil {
+		return rawConn, nil
+	}
+
+	tlsConn := tls.Client(rawConn, d.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// connector wraps a *pq.Connector so every new connection gets
+// application_name and statement_timeout applied via SET, independent of
+// DSN and server defaults.
+type connector struct {
+	pq               *pq.Connector
+	applicationName  string
+	statementTimeout time.Duration
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.pq.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return conn, nil
+	}
+
+	if c.applicationName != "" {
+		stmt := fmt.Sprintf("SET application_name = %s", pq.QuoteLiteral(c.applicationName))
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set application_name: %w", err)
+		}
+	}
+
+	if c.statementTimeout > 0 {
+		stmt := fmt.Sprintf("SET statement_timeout = %d", c.statementTimeout.Milliseconds())
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.pq.Driver()
+}
+
+// openDB opens *sql.DB for f.dsn through a wrapping Connector instead of
+// plain sql.Open, so WithTLSConfig/WithConnectTimeout/WithApplicationName/
+// WithStatementTimeout take effect on every connection the pool opens.
+func (f *Factory) openDB() (*sql.DB, error) {
+	pqConnector, err := pq.NewConnector(f.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.tlsConfig != nil || f.connectTimeout > 0 {
+		pqConnector.Dialer(&tlsDialer{tlsConfig: f.tlsConfig, connectTimeout: f.connectTimeout})
+	}
+
+	return sql.OpenDB(&connector{
+		pq:               pqConnector,
+		applicationName:  f.applicationName,
+		statementTimeout: f.statementTimeout,
+	}), nil
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFactory_ConnectTimeoutConnector(t *testing.T) {
+	dsn := getTestDSN()
+
+	factory := NewFactory(dsn).
+		WithTableName("connector_test_data").
+		WithConnectTimeout(5 * time.Second).
+		WithApplicationName("retryspool-storage-data-postgres-test")
+
+	backend, err := factory.Create()
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	defer cleanupTestDB(t, backend.(*Backend))
+}
@@ -1,30 +1,77 @@
 package postgres
 
 import (
-	"database/sql"
-	
+	"context"
+	"crypto/tls"
+	"time"
+
 	datastorage "schneider.vip/retryspool/storage/data"
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"schneider.vip/retryspool/storage/data/postgres/migrations"
 )
 
 // Factory creates PostgreSQL data storage backends
 type Factory struct {
-	dsn           string
-	tableName     string
-	maxOpenConns  int
-	maxIdleConns  int
+	dsn              string
+	tableName        string
+	maxOpenConns     int
+	maxIdleConns     int
+	storageMode      StorageMode
+	migrationPolicy  migrations.Policy
+	maxBatchRowBytes int64
+	tlsConfig        *tls.Config
+	connectTimeout   time.Duration
+	applicationName  string
+	statementTimeout time.Duration
 }
 
 // NewFactory creates a new PostgreSQL data storage factory
 func NewFactory(dsn string) *Factory {
 	return &Factory{
-		dsn:          dsn,
-		tableName:    "retryspool_data",
-		maxOpenConns: 25,
-		maxIdleConns: 5,
+		dsn:             dsn,
+		tableName:       "retryspool_data",
+		maxOpenConns:    25,
+		maxIdleConns:    5,
+		storageMode:     ModeBytea,
+		migrationPolicy: migrations.Auto,
 	}
 }
 
+// WithMigrations selects how Factory.Create applies the versioned schema
+// migrations in the migrations sub-package. The default, migrations.Auto,
+// applies any pending migrations automatically, matching the historical
+// CREATE TABLE IF NOT EXISTS behavior.
+func (f *Factory) WithMigrations(policy migrations.Policy) *Factory {
+	f.migrationPolicy = policy
+	return f
+}
+
+// Migrate applies any pending schema migrations for this factory's table,
+// for use with migrations.RequireUpToDate or migrations.Manual policies
+// where Create does not do so itself.
+func (f *Factory) Migrate(ctx context.Context) error {
+	db, err := f.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	backend := &Backend{db: db, dsn: f.dsn, tableName: f.tableName, mode: f.storageMode}
+	if err := migrations.NewRunner(db, backend.migrationTemplateData()).Up(ctx); err != nil {
+		return err
+	}
+	return backend.installNotifyTrigger()
+}
+
+// WithStorageMode selects how message payloads are physically stored.
+// ModeBytea (the default) is simplest but buffers whole payloads in
+// memory; ModeLargeObject streams payloads through PostgreSQL large
+// objects instead. Switching an existing table's mode requires running
+// Backend.MigrateToLargeObjects first.
+func (f *Factory) WithStorageMode(mode StorageMode) *Factory {
+	f.storageMode = mode
+	return f
+}
+
 // WithTableName sets a custom table name for message data
 func (f *Factory) WithTableName(tableName string) *Factory {
 	f.tableName = tableName
@@ -38,9 +85,52 @@ func (f *Factory) WithConnectionLimits(maxOpen, maxIdle int) *Factory {
 	return f
 }
 
+// WithMaxBatchRowBytes caps the size of any single message's payload when
+// using Backend.StoreBatch, so one oversized message can't blow up the
+// batch's temporary table. A value of 0 (the default) means no limit.
+func (f *Factory) WithMaxBatchRowBytes(maxBytes int64) *Factory {
+	f.maxBatchRowBytes = maxBytes
+	return f
+}
+
+// WithTLSConfig sets a custom TLS configuration used to establish every
+// connection in the backend's connection pool, for when certificates come
+// from a secret store rather than the filesystem (lib/pq's own
+// sslrootcert/sslcert/sslkey DSN parameters only accept file paths). Set
+// it together with sslmode=disable in the DSN: the handshake happens
+// before lib/pq sees the connection, so its own TLS negotiation must be
+// turned off. Backend.Subscribe's listener connects with the DSN alone and
+// does not use this configuration.
+func (f *Factory) WithTLSConfig(cfg *tls.Config) *Factory {
+	f.tlsConfig = cfg
+	return f
+}
+
+// WithConnectTimeout sets how long to wait when establishing a new
+// connection.
+func (f *Factory) WithConnectTimeout(timeout time.Duration) *Factory {
+	f.connectTimeout = timeout
+	return f
+}
+
+// WithApplicationName sets application_name on every connection in the
+// backend's connection pool, so it shows up in pg_stat_activity and server
+// logs. Backend.Subscribe's listener connection does not get it.
+func (f *Factory) WithApplicationName(name string) *Factory {
+	f.applicationName = name
+	return f
+}
+
+// WithStatementTimeout sets statement_timeout on every connection,
+// aborting any single query that runs longer than timeout.
+func (f *Factory) WithStatementTimeout(timeout time.Duration) *Factory {
+	f.statementTimeout = timeout
+	return f
+}
+
 // Create creates a new PostgreSQL data storage backend
 func (f *Factory) Create() (datastorage.Backend, error) {
-	db, err := sql.Open("postgres", f.dsn)
+	db, err := f.openDB()
 	if err != nil {
 		return nil, err
 	}
@@ -56,14 +146,39 @@ func (f *Factory) Create() (datastorage.Backend, error) {
 	}
 
 	backend := &Backend{
-		db:        db,
-		tableName: f.tableName,
+		db:               db,
+		dsn:              f.dsn,
+		tableName:        f.tableName,
+		mode:             f.storageMode,
+		maxBatchRowBytes: f.maxBatchRowBytes,
 	}
 
-	// Create table if it doesn't exist
-	if err := backend.createTable(); err != nil {
-		db.Close()
-		return nil, err
+	runner := migrations.NewRunner(db, backend.migrationTemplateData())
+	ctx := context.Background()
+	switch f.migrationPolicy {
+	case migrations.RequireUpToDate:
+		// Schema, including the LISTEN/NOTIFY trigger, is expected to
+		// already be up to date via Factory.Migrate.
+		if err := runner.EnsureUpToDate(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	case migrations.Manual:
+		// Schema is expected to already be migrated via Factory.Migrate.
+	default:
+		// Auto: apply pending migrations and (re)install the notify
+		// trigger ourselves, matching the historical behavior. The other
+		// policies apply no migrations and install no trigger from Create;
+		// RequireUpToDate only reads the applied version, creating the
+		// empty migration tracking table if it is missing.
+		if err := runner.Up(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+		if err := backend.installNotifyTrigger(); err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
 
 	return backend, nil
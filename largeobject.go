@@ -0,0 +1,429 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// Large object access modes, as defined by PostgreSQL's libpq fe-lobj.c.
+const (
+	loModeWrite = 0x20000 // INV_WRITE
+	loModeRead  = 0x40000 // INV_READ
+)
+
+// loChunkSize is the amount of data read from / written to a large object
+// per round-trip.
+const loChunkSize = 8192
+
+// storeDataLO stores message data using PostgreSQL large objects instead of
+// a BYTEA column. The large object and the row upsert happen in the same
+// transaction so a failure never leaves an orphaned large object behind.
+func (b *Backend) storeDataLO(ctx context.Context, messageID string, data io.Reader) (int64, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := b.unlinkExistingLO(ctx, tx, messageID); err != nil {
+		return 0, err
+	}
+
+	oid, size, err := writeLargeObject(ctx, tx, data)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := b.upsertLORow(ctx, tx, messageID, oid, size); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return size, nil
+}
+
+// unlinkExistingLO removes the large object currently referenced by
+// messageID, if any, so StoreData can be used to overwrite existing data
+// without leaking large objects.
+func (b *Backend) unlinkExistingLO(ctx context.Context, tx *sql.Tx, messageID string) error {
+	var existingOID uint32
+	query := fmt.Sprintf(`SELECT data FROM %s WHERE message_id = $1 FOR UPDATE`, b.tableName)
+	err := tx.QueryRowContext(ctx, query, messageID).Scan(&existingOID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up existing large object for message %s: %w", messageID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT lo_unlink($1)`, existingOID); err != nil {
+		return fmt.Errorf("failed to unlink large object %d for message %s: %w", existingOID, messageID, err)
+	}
+	return nil
+}
+
+// writeLargeObject creates a new large object within tx and streams r into
+// it in loChunkSize pieces, returning the new OID and the total size
+// written.
+func writeLargeObject(ctx context.Context, tx *sql.Tx, r io.Reader) (oid uint32, size int64, err error) {
+	if err = tx.QueryRowContext(ctx, `SELECT lo_create(0)`).Scan(&oid); err != nil {
+		return 0, 0, fmt.Errorf("failed to create large object: %w", err)
+	}
+
+	var fd int32
+	if err = tx.QueryRowContext(ctx, `SELECT lo_open($1, $2)`, oid, loModeWrite).Scan(&fd); err != nil {
+		return 0, 0, fmt.Errorf("failed to open large object %d for writing: %w", oid, err)
+	}
+
+	buf := make([]byte, loChunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if _, err = tx.ExecContext(ctx, `SELECT lowrite($1, $2)`, fd, buf[:n]); err != nil {
+				return 0, 0, fmt.Errorf("failed to write to large object %d: %w", oid, err)
+			}
+			size += int64(n)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return 0, 0, fmt.Errorf("failed to read data: %w", readErr)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `SELECT lo_close($1)`, fd); err != nil {
+		return 0, 0, fmt.Errorf("failed to close large object %d: %w", oid, err)
+	}
+
+	return oid, size, nil
+}
+
+func (b *Backend) upsertLORow(ctx context.Context, tx *sql.Tx, messageID string, oid uint32, size int64) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (message_id, data, size, created, updated)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (message_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			size = EXCLUDED.size,
+			updated = EXCLUDED.updated
+	`, b.tableName)
+
+	_, err := tx.ExecContext(ctx, query, messageID, oid, size)
+	if err != nil {
+		return fmt.Errorf("failed to store large object reference for message %s: %w", messageID, err)
+	}
+	return nil
+}
+
+// getDataReaderLO returns an io.ReadCloser that streams a large object's
+// contents. The backing transaction and large object descriptor are held
+// open until Close is called.
+func (b *Backend) getDataReaderLO(ctx context.Context, messageID string) (io.ReadCloser, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var oid uint32
+	query := fmt.Sprintf(`SELECT data FROM %s WHERE message_id = $1`, b.tableName)
+	if err := tx.QueryRowContext(ctx, query, messageID).Scan(&oid); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("data for message %s not found", messageID)
+		}
+		return nil, fmt.Errorf("failed to get data for message %s: %w", messageID, err)
+	}
+
+	var fd int32
+	if err := tx.QueryRowContext(ctx, `SELECT lo_open($1, $2)`, oid, loModeRead).Scan(&fd); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to open large object %d for reading: %w", oid, err)
+	}
+
+	return &loReadCloser{ctx: ctx, tx: tx, fd: fd}, nil
+}
+
+// loReadCloser reads a large object in loChunkSize pieces and owns the
+// transaction it was opened under. PostgreSQL large object operations are
+// only valid inside a transaction, so Close commits (or rolls back) it.
+type loReadCloser struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	fd     int32
+	eof    bool
+	closed bool
+}
+
+func (lrc *loReadCloser) Read(p []byte) (int, error) {
+	if lrc.eof {
+		return 0, io.EOF
+	}
+
+	chunk, err := readLargeObjectChunk(lrc.ctx, lrc.tx, lrc.fd, len(p))
+	if err != nil {
+		return 0, err
+	}
+	if len(chunk) < minInt(len(p), loChunkSize) {
+		lrc.eof = true
+	}
+	if len(chunk) == 0 {
+		return 0, io.EOF
+	}
+
+	return copy(p, chunk), nil
+}
+
+// readLargeObjectChunk reads up to want bytes (capped at loChunkSize) from
+// the large object identified by fd, within tx.
+func readLargeObjectChunk(ctx context.Context, tx *sql.Tx, fd int32, want int) ([]byte, error) {
+	want = minInt(want, loChunkSize)
+
+	var chunk []byte
+	if err := tx.QueryRowContext(ctx, `SELECT loread($1, $2)`, fd, want).Scan(&chunk); err != nil {
+		return nil, fmt.Errorf("failed to read from large object: %w", err)
+	}
+	return chunk, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (lrc *loReadCloser) Close() error {
+	if lrc.closed {
+		return nil
+	}
+	lrc.closed = true
+
+	_, _ = lrc.tx.ExecContext(lrc.ctx, `SELECT lo_close($1)`, lrc.fd)
+	return lrc.tx.Commit()
+}
+
+// getDataWriterLO returns an io.WriteCloser that streams directly into a
+// large object instead of accumulating an in-memory buffer.
+func (b *Backend) getDataWriterLO(ctx context.Context, messageID string) (io.WriteCloser, error) {
+	return &loWriteCloser{ctx: ctx, backend: b, messageID: messageID}, nil
+}
+
+// loWriteCloser buffers writes only up to loChunkSize before flushing them
+// to the large object, so memory use stays bounded regardless of payload
+// size. The large object and row upsert share one transaction, opened
+// lazily on the first Write so Close on an empty writer is a no-op like the
+// BYTEA-backed writer.
+type loWriteCloser struct {
+	ctx       context.Context
+	backend   *Backend
+	messageID string
+
+	tx     *sql.Tx
+	oid    uint32
+	fd     int32
+	buf    []byte
+	size   int64
+	closed bool
+}
+
+func (lwc *loWriteCloser) ensureOpen() error {
+	if lwc.tx != nil {
+		return nil
+	}
+
+	tx, err := lwc.backend.db.BeginTx(lwc.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := lwc.backend.unlinkExistingLO(lwc.ctx, tx, lwc.messageID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var oid uint32
+	if err := tx.QueryRowContext(lwc.ctx, `SELECT lo_create(0)`).Scan(&oid); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create large object: %w", err)
+	}
+
+	var fd int32
+	if err := tx.QueryRowContext(lwc.ctx, `SELECT lo_open($1, $2)`, oid, loModeWrite).Scan(&fd); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to open large object %d for writing: %w", oid, err)
+	}
+
+	lwc.tx, lwc.oid, lwc.fd = tx, oid, fd
+	return nil
+}
+
+func (lwc *loWriteCloser) Write(p []byte) (int, error) {
+	if lwc.closed {
+		return 0, fmt.Errorf("writer is closed")
+	}
+	if err := lwc.ensureOpen(); err != nil {
+		return 0, err
+	}
+
+	for rest := p; len(rest) > 0; {
+		n := minInt(len(rest), loChunkSize-len(lwc.buf))
+		lwc.buf = append(lwc.buf, rest[:n]...)
+		rest = rest[n:]
+		if len(lwc.buf) == loChunkSize {
+			if err := lwc.flush(loChunkSize); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (lwc *loWriteCloser) flush(n int) error {
+	if _, err := lwc.tx.ExecContext(lwc.ctx, `SELECT lowrite($1, $2)`, lwc.fd, lwc.buf[:n]); err != nil {
+		return fmt.Errorf("failed to write to large object %d: %w", lwc.oid, err)
+	}
+	lwc.size += int64(n)
+	lwc.buf = lwc.buf[n:]
+	return nil
+}
+
+func (lwc *loWriteCloser) Close() error {
+	if lwc.closed {
+		return nil
+	}
+	lwc.closed = true
+
+	if lwc.tx == nil {
+		return nil
+	}
+
+	if len(lwc.buf) > 0 {
+		if err := lwc.flush(len(lwc.buf)); err != nil {
+			lwc.tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := lwc.tx.ExecContext(lwc.ctx, `SELECT lo_close($1)`, lwc.fd); err != nil {
+		lwc.tx.Rollback()
+		return fmt.Errorf("failed to close large object %d: %w", lwc.oid, err)
+	}
+
+	if err := lwc.backend.upsertLORow(lwc.ctx, lwc.tx, lwc.messageID, lwc.oid, lwc.size); err != nil {
+		lwc.tx.Rollback()
+		return err
+	}
+
+	if err := lwc.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// deleteDataLO removes a message's large object and its row in one
+// transaction.
+func (b *Backend) deleteDataLO(ctx context.Context, messageID string) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oid uint32
+	query := fmt.Sprintf(`SELECT data FROM %s WHERE message_id = $1`, b.tableName)
+	err = tx.QueryRowContext(ctx, query, messageID).Scan(&oid)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("data for message %s not found", messageID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up large object for message %s: %w", messageID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT lo_unlink($1)`, oid); err != nil {
+		return fmt.Errorf("failed to unlink large object %d for message %s: %w", oid, messageID, err)
+	}
+
+	deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE message_id = $1`, b.tableName)
+	if _, err := tx.ExecContext(ctx, deleteQuery, messageID); err != nil {
+		return fmt.Errorf("failed to delete data for message %s: %w", messageID, err)
+	}
+
+	return tx.Commit()
+}
+
+// MigrateToLargeObjects converts every row in the table from an in-line
+// BYTEA payload to a PostgreSQL large object, rewriting the data column
+// from BYTEA to OID in place. It is intended as a one-time, offline
+// conversion when switching an existing table from ModeBytea to
+// ModeLargeObject; run it before serving traffic with the new mode.
+func (b *Backend) MigrateToLargeObjects(ctx context.Context) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	addColumn := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN data_oid OID`, b.tableName)
+	if _, err := tx.ExecContext(ctx, addColumn); err != nil {
+		return fmt.Errorf("failed to add data_oid column: %w", err)
+	}
+
+	selectQuery := fmt.Sprintf(`SELECT message_id, data FROM %s`, b.tableName)
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if err != nil {
+		return fmt.Errorf("failed to read existing rows: %w", err)
+	}
+
+	type pending struct {
+		messageID string
+		data      []byte
+	}
+	var toMigrate []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.messageID, &p.data); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		toMigrate = append(toMigrate, p)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	rows.Close()
+
+	updateQuery := fmt.Sprintf(`UPDATE %s SET data_oid = $1 WHERE message_id = $2`, b.tableName)
+	for _, p := range toMigrate {
+		var oid uint32
+		if err := tx.QueryRowContext(ctx, `SELECT lo_from_bytea(0, $1)`, p.data).Scan(&oid); err != nil {
+			return fmt.Errorf("failed to create large object for message %s: %w", p.messageID, err)
+		}
+		if _, err := tx.ExecContext(ctx, updateQuery, oid, p.messageID); err != nil {
+			return fmt.Errorf("failed to set data_oid for message %s: %w", p.messageID, err)
+		}
+	}
+
+	dropColumn := fmt.Sprintf(`ALTER TABLE %s DROP COLUMN data`, b.tableName)
+	if _, err := tx.ExecContext(ctx, dropColumn); err != nil {
+		return fmt.Errorf("failed to drop data column: %w", err)
+	}
+
+	renameColumn := fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN data_oid TO data`, b.tableName)
+	if _, err := tx.ExecContext(ctx, renameColumn); err != nil {
+		return fmt.Errorf("failed to rename data_oid column: %w", err)
+	}
+
+	setNotNull := fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN data SET NOT NULL`, b.tableName)
+	if _, err := tx.ExecContext(ctx, setNotNull); err != nil {
+		return fmt.Errorf("failed to mark data column NOT NULL: %w", err)
+	}
+
+	return tx.Commit()
+}
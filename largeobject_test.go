@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+)
+
+func setupTestDBMode(t *testing.T, tableName string, mode StorageMode) *Backend {
+	dsn := getTestDSN()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+
+	factory := NewFactory(dsn).WithTableName(tableName).WithStorageMode(mode)
+	backend, err := factory.Create()
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	return backend.(*Backend)
+}
+
+func TestBackend_LargeObject_StoreReadDelete(t *testing.T) {
+	backend := setupTestDBMode(t, "test_data_lo", ModeLargeObject)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	messageID := "lo-message-1"
+	testData := strings.Repeat("large object round trip data\n", 500)
+
+	size, err := backend.StoreData(ctx, messageID, strings.NewReader(testData))
+	if err != nil {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+	if size != int64(len(testData)) {
+		t.Errorf("Size mismatch: expected %d, got %d", len(testData), size)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get data reader: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	if string(got) != testData {
+		t.Errorf("Data mismatch (lengths: expected %d, got %d)", len(testData), len(got))
+	}
+
+	// Overwriting must replace the stored data with the new payload.
+	updated := "replacement data"
+	if _, err := backend.StoreData(ctx, messageID, strings.NewReader(updated)); err != nil {
+		t.Fatalf("Failed to overwrite data: %v", err)
+	}
+	reader, err = backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get data reader after overwrite: %v", err)
+	}
+	got, err = io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to read updated data: %v", err)
+	}
+	if string(got) != updated {
+		t.Errorf("Updated data mismatch: expected %s, got %s", updated, string(got))
+	}
+
+	if err := backend.DeleteData(ctx, messageID); err != nil {
+		t.Fatalf("Failed to delete data: %v", err)
+	}
+	if _, err := backend.GetDataReader(ctx, messageID); err == nil {
+		t.Error("Expected error reading deleted large object message")
+	}
+}
+
+func TestBackend_LargeObject_Writer(t *testing.T) {
+	backend := setupTestDBMode(t, "test_data_lo_writer", ModeLargeObject)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	messageID := "lo-message-writer"
+
+	writer, err := backend.GetDataWriter(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get data writer: %v", err)
+	}
+	chunk1 := strings.Repeat("a", loChunkSize+100)
+	chunk2 := "tail"
+	if _, err := writer.Write([]byte(chunk1)); err != nil {
+		t.Fatalf("Failed to write first chunk: %v", err)
+	}
+	if _, err := writer.Write([]byte(chunk2)); err != nil {
+		t.Fatalf("Failed to write second chunk: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get data reader: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read written data: %v", err)
+	}
+	if string(got) != chunk1+chunk2 {
+		t.Errorf("Written data mismatch (lengths: expected %d, got %d)", len(chunk1)+len(chunk2), len(got))
+	}
+}
+
+func TestBackend_MigrateToLargeObjects(t *testing.T) {
+	backend := setupTestDB(t)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	messageID := "migrate-to-lo-message"
+	testData := "data that starts as BYTEA and ends as a large object"
+
+	if _, err := backend.StoreData(ctx, messageID, strings.NewReader(testData)); err != nil {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+
+	if err := backend.MigrateToLargeObjects(ctx); err != nil {
+		t.Fatalf("Failed to migrate to large objects: %v", err)
+	}
+	backend.mode = ModeLargeObject
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get data reader after migration: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read migrated data: %v", err)
+	}
+	if string(got) != testData {
+		t.Errorf("Migrated data mismatch: expected %s, got %s", testData, string(got))
+	}
+}
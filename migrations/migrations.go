@@ -0,0 +1,301 @@
+// Package migrations implements a small, golang-migrate-style versioned
+// schema migration runner for the postgres data storage backend. Each
+// backend table gets its own dedicated schema_migrations table and
+// advisory lock, keyed off the table name, so multiple backends can share
+// one database safely.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// Policy controls how Factory.Create applies pending migrations.
+type Policy int
+
+const (
+	// Auto applies any pending migrations automatically when the backend
+	// is created. This matches the historical CREATE TABLE IF NOT EXISTS
+	// behavior and is the default.
+	Auto Policy = iota
+	// RequireUpToDate refuses to create a backend unless the schema is
+	// already at the latest migration version. Run Factory.Migrate out
+	// of band to apply pending migrations.
+	RequireUpToDate
+	// Manual never touches the schema from Factory.Create; migrations
+	// must be applied explicitly via Factory.Migrate.
+	Manual
+)
+
+// TemplateData supplies the per-backend values referenced by migration SQL.
+type TemplateData struct {
+	// Table is the data table name.
+	Table string
+	// DataColumn is the column definition for the payload column, e.g.
+	// "data BYTEA NOT NULL" or "data OID NOT NULL".
+	DataColumn string
+	// Bytea is true when the payload column is BYTEA, enabling
+	// migrations that can only run against inline data (e.g. backfilling
+	// a checksum by hashing the column directly).
+	Bytea bool
+}
+
+// execer is the subset of *sql.DB / *sql.Conn that the runner needs while
+// holding the advisory lock. Using this instead of *sql.DB directly lets Up
+// pin a single physical connection for the lock, the migrations, and the
+// unlock, since pg_advisory_lock is session-scoped and would otherwise leak
+// onto an idle pooled connection and never release.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+type migration struct {
+	version int64
+	name    string
+	upSQL   string
+	downSQL string
+}
+
+var filenameRE = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+func loadMigrations() ([]migration, error) {
+	entries, err := sqlFS.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		m := filenameRE.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration filename %s: %w", entry.Name(), err)
+		}
+
+		content, err := sqlFS.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{version: version, name: m[2]}
+			byVersion[version] = mig
+		}
+		if m[3] == "up" {
+			mig.upSQL = string(content)
+		} else {
+			mig.downSQL = string(content)
+		}
+	}
+
+	result := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		result = append(result, *mig)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
+
+func render(sqlText string, data TemplateData) (string, error) {
+	tmpl, err := template.New("migration").Parse(sqlText)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Runner applies versioned migrations for a single backend table,
+// coordinating with other processes via a PostgreSQL advisory lock and
+// tracking applied versions in a dedicated schema_migrations table.
+type Runner struct {
+	db   *sql.DB
+	data TemplateData
+}
+
+// NewRunner creates a migration runner for the given table.
+func NewRunner(db *sql.DB, data TemplateData) *Runner {
+	return &Runner{db: db, data: data}
+}
+
+func (r *Runner) schemaMigrationsTable() string {
+	return fmt.Sprintf("%s_schema_migrations", r.data.Table)
+}
+
+func (r *Runner) lockName() string {
+	return fmt.Sprintf("retryspool_data_%s", r.data.Table)
+}
+
+// withAdvisoryLock acquires the table's advisory lock on conn, runs fn, and
+// releases the lock, all on that same physical connection. pg_advisory_lock
+// is session-scoped, so lock and unlock must happen on the same connection;
+// going through the *sql.DB pool would let the driver hand the locked
+// connection back to a different caller before it's unlocked.
+func (r *Runner) withAdvisoryLock(ctx context.Context, conn *sql.Conn, fn func() error) error {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, r.lockName()); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, r.lockName())
+
+	return fn()
+}
+
+func (r *Runner) ensureSchemaMigrationsTable(ctx context.Context, exec execer) error {
+	query := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		version BIGINT PRIMARY KEY,
+		dirty BOOLEAN NOT NULL DEFAULT false,
+		applied TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`, r.schemaMigrationsTable())
+	_, err := exec.ExecContext(ctx, query)
+	return err
+}
+
+// CurrentVersion returns the highest applied migration version, or 0 if
+// none have been applied yet. dirty is true if a previous migration
+// attempt failed partway through and needs manual repair.
+func (r *Runner) CurrentVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	return r.currentVersion(ctx, r.db)
+}
+
+func (r *Runner) currentVersion(ctx context.Context, exec execer) (version int64, dirty bool, err error) {
+	if err = r.ensureSchemaMigrationsTable(ctx, exec); err != nil {
+		return 0, false, err
+	}
+
+	query := fmt.Sprintf(`SELECT version, dirty FROM %s ORDER BY version DESC LIMIT 1`, r.schemaMigrationsTable())
+	err = exec.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read current migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Up applies every pending migration in order, holding a PostgreSQL
+// advisory lock for the duration so concurrent processes migrating the
+// same table don't race each other. The lock, the migrations, and the
+// unlock all run on a single pinned connection.
+func (r *Runner) Up(ctx context.Context) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire a connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	return r.withAdvisoryLock(ctx, conn, func() error {
+		pending, err := loadMigrations()
+		if err != nil {
+			return err
+		}
+
+		current, dirty, err := r.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d for %s is marked dirty; needs manual repair before continuing", current, r.data.Table)
+		}
+
+		for _, mig := range pending {
+			if mig.version <= current {
+				continue
+			}
+			if err := r.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, mig migration) error {
+	upSQL, err := render(mig.upSQL, r.data)
+	if err != nil {
+		return fmt.Errorf("failed to render migration %d (%s): %w", mig.version, mig.name, err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %w", mig.version, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, upSQL); err != nil {
+		tx.Rollback()
+		r.markDirty(ctx, conn, mig.version)
+		return fmt.Errorf("migration %d (%s) failed: %w", mig.version, mig.name, err)
+	}
+
+	recordQuery := fmt.Sprintf(`
+		INSERT INTO %s (version, dirty, applied) VALUES ($1, false, NOW())
+		ON CONFLICT (version) DO UPDATE SET dirty = false, applied = NOW()
+	`, r.schemaMigrationsTable())
+	if _, err := tx.ExecContext(ctx, recordQuery, mig.version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %d: %w", mig.version, err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *Runner) markDirty(ctx context.Context, exec execer, version int64) {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (version, dirty, applied) VALUES ($1, true, NOW())
+		ON CONFLICT (version) DO UPDATE SET dirty = true
+	`, r.schemaMigrationsTable())
+	exec.ExecContext(ctx, query, version)
+}
+
+// LatestVersion returns the newest migration version embedded in this
+// binary.
+func LatestVersion() (int64, error) {
+	pending, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	return pending[len(pending)-1].version, nil
+}
+
+// EnsureUpToDate returns an error if the schema is not already at
+// LatestVersion. Unlike Up, it never applies anything itself.
+func (r *Runner) EnsureUpToDate(ctx context.Context) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	current, dirty, err := r.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d for %s is marked dirty; needs manual repair", current, r.data.Table)
+	}
+	if current < latest {
+		return fmt.Errorf("schema for %s is at version %d, need %d; run Factory.Migrate", r.data.Table, current, latest)
+	}
+	return nil
+}
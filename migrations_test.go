@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"schneider.vip/retryspool/storage/data/postgres/migrations"
+)
+
+func TestFactory_MigrationPolicies(t *testing.T) {
+	dsn := getTestDSN()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	db.Close()
+
+	tableName := "migration_policy_test_data"
+	ctx := context.Background()
+
+	// Manual never touches the schema, so Create succeeds but leaves the
+	// table uncreated until Migrate has run.
+	manualFactory := NewFactory(dsn).WithTableName(tableName).WithMigrations(migrations.Manual)
+	unmigrated, err := manualFactory.Create()
+	if err != nil {
+		t.Fatalf("Failed to create backend with migrations.Manual: %v", err)
+	}
+	_, err = unmigrated.(*Backend).db.ExecContext(ctx, fmt.Sprintf("SELECT 1 FROM %s", tableName))
+	unmigrated.Close()
+	if err == nil {
+		t.Fatal("Expected Create with migrations.Manual not to create the table before Migrate has run")
+	}
+
+	if err := manualFactory.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to run Migrate: %v", err)
+	}
+
+	backend, err := manualFactory.Create()
+	if err != nil {
+		t.Fatalf("Failed to create backend after Migrate: %v", err)
+	}
+	defer cleanupTestDB(t, backend.(*Backend))
+
+	// RequireUpToDate should now succeed since the schema is current.
+	requireFactory := NewFactory(dsn).WithTableName(tableName).WithMigrations(migrations.RequireUpToDate)
+	requireBackend, err := requireFactory.Create()
+	if err != nil {
+		t.Fatalf("Expected Create with migrations.RequireUpToDate to succeed on an up-to-date schema: %v", err)
+	}
+	requireBackend.Close()
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Op identifies the kind of change a notification describes.
+type Op string
+
+const (
+	OpInsert Op = "insert"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Event describes a single change to a row in the backend's table.
+type Event struct {
+	Op        Op
+	MessageID string
+	Size      int64
+	At        time.Time
+}
+
+// notifyChannel returns the LISTEN/NOTIFY channel name for this backend's
+// table.
+func (b *Backend) notifyChannel() string {
+	return fmt.Sprintf("retryspool_data_%s", b.tableName)
+}
+
+// installNotifyTrigger installs a trigger function that publishes every
+// insert, update and delete on the table via pg_notify so callers can react
+// to storage changes without polling.
+func (b *Backend) installNotifyTrigger() error {
+	funcName := fmt.Sprintf("%s_notify", b.tableName)
+	triggerName := fmt.Sprintf("%s_notify_trigger", b.tableName)
+
+	query := fmt.Sprintf(`
+	CREATE OR REPLACE FUNCTION %[1]s() RETURNS TRIGGER AS $$
+	DECLARE
+		payload JSON;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			payload := json_build_object('op', 'delete', 'message_id', OLD.message_id, 'size', OLD.size, 'at', extract(epoch from now()));
+			PERFORM pg_notify(%[3]s, payload::text);
+			RETURN OLD;
+		ELSIF TG_OP = 'UPDATE' THEN
+			payload := json_build_object('op', 'update', 'message_id', NEW.message_id, 'size', NEW.size, 'at', extract(epoch from NEW.updated));
+			PERFORM pg_notify(%[3]s, payload::text);
+			RETURN NEW;
+		ELSE
+			payload := json_build_object('op', 'insert', 'message_id', NEW.message_id, 'size', NEW.size, 'at', extract(epoch from NEW.created));
+			PERFORM pg_notify(%[3]s, payload::text);
+			RETURN NEW;
+		END IF;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS %[2]s ON %[4]s;
+	CREATE TRIGGER %[2]s
+	AFTER INSERT OR UPDATE OR DELETE ON %[4]s
+	FOR EACH ROW EXECUTE FUNCTION %[1]s();
+	`, funcName, triggerName, pq.QuoteLiteral(b.notifyChannel()), b.tableName)
+
+	_, err := b.db.Exec(query)
+	return err
+}
+
+// Subscribe registers handler to be called for every insert, update and
+// delete on the backend's table. It blocks until the subscription is
+// established (the LISTEN has been issued) and then delivers events in the
+// background until ctx is cancelled.
+//
+// lib/pq's Listener reconnects automatically and reports a lost connection
+// by sending a nil notification; since notifications sent while
+// disconnected are lost, Subscribe reconciles by fetching rows updated
+// since the last event it saw. This fallback cannot observe deletes that
+// happened while disconnected, only inserts/updates.
+func (b *Backend) Subscribe(ctx context.Context, handler func(Event)) error {
+	listener := pq.NewListener(b.dsn, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(b.notifyChannel()); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to listen on channel %s: %w", b.notifyChannel(), err)
+	}
+
+	go func() {
+		defer listener.Close()
+
+		lastSeen := time.Now()
+		ticker := time.NewTicker(90 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					// Connection was lost and has been re-established;
+					// notifications sent in between were not delivered.
+					b.reconcileMissed(ctx, lastSeen, handler)
+					lastSeen = time.Now()
+					continue
+				}
+
+				var payload struct {
+					Op        string  `json:"op"`
+					MessageID string  `json:"message_id"`
+					Size      int64   `json:"size"`
+					At        float64 `json:"at"`
+				}
+				if err := json.Unmarshal([]byte(n.Extra), &payload); err != nil {
+					continue
+				}
+
+				at := time.Unix(0, int64(payload.At*float64(time.Second)))
+				handler(Event{Op: Op(payload.Op), MessageID: payload.MessageID, Size: payload.Size, At: at})
+				if at.After(lastSeen) {
+					lastSeen = at
+				}
+			case <-ticker.C:
+				// Detect half-open connections per lib/pq's recommendation.
+				_ = listener.Ping()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reconcileMissed fetches rows updated since 'since' and replays them as
+// synthetic update events, for use after a listener reports a lost
+// connection.
+func (b *Backend) reconcileMissed(ctx context.Context, since time.Time, handler func(Event)) {
+	query := fmt.Sprintf(`SELECT message_id, size, updated FROM %s WHERE updated > $1 ORDER BY updated`, b.tableName)
+	rows, err := b.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID string
+		var size int64
+		var updated time.Time
+		if err := rows.Scan(&messageID, &size, &updated); err != nil {
+			continue
+		}
+		handler(Event{Op: OpUpdate, MessageID: messageID, Size: size, At: updated})
+	}
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackend_Subscribe(t *testing.T) {
+	backend := setupTestDB(t)
+	defer cleanupTestDB(t, backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 8)
+	if err := backend.Subscribe(ctx, func(ev Event) {
+		events <- ev
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// Subscribe's LISTEN is issued synchronously, but give PostgreSQL a
+	// moment to finish registering it before we generate notifications.
+	time.Sleep(100 * time.Millisecond)
+
+	messageID := "test-notify-message"
+	if _, err := backend.StoreData(ctx, messageID, strings.NewReader("notify me")); err != nil {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Op != OpInsert {
+			t.Errorf("Expected op %q, got %q", OpInsert, ev.Op)
+		}
+		if ev.MessageID != messageID {
+			t.Errorf("Expected message ID %s, got %s", messageID, ev.MessageID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for insert notification")
+	}
+
+	if err := backend.DeleteData(ctx, messageID); err != nil {
+		t.Fatalf("Failed to delete data: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Op != OpDelete {
+			t.Errorf("Expected op %q, got %q", OpDelete, ev.Op)
+		}
+		if ev.MessageID != messageID {
+			t.Errorf("Expected message ID %s, got %s", messageID, ev.MessageID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delete notification")
+	}
+}
@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SnapshotView exposes consistent, read-only access to every message as it
+// existed when the first query ran in WithSnapshot's transaction
+// (PostgreSQL takes a REPEATABLE READ snapshot at the first query, not at
+// BEGIN).
+type SnapshotView interface {
+	// GetDataReader returns a reader for a single message's data.
+	GetDataReader(ctx context.Context, messageID string) (io.ReadCloser, error)
+	// Size returns a message's stored size without reading its data.
+	Size(ctx context.Context, messageID string) (int64, error)
+	// Exists reports whether a message is present in the snapshot.
+	Exists(ctx context.Context, messageID string) (bool, error)
+	// Iterate returns a cursor over messages whose ID starts with prefix
+	// and whose updated timestamp is after since. Pass "" and the zero
+	// time to iterate everything.
+	Iterate(ctx context.Context, prefix string, since time.Time) (*SnapshotCursor, error)
+}
+
+// WithSnapshot runs fn against a SnapshotView backed by a single
+// REPEATABLE READ, read-only transaction, so every call fn makes sees the
+// same consistent view of the table even while producers keep
+// writing/deleting concurrently. fn's error is returned after the
+// transaction is rolled back; a nil error commits it.
+//
+// Because the transaction holds open an MVCC snapshot, callers should not
+// keep it open for very long (e.g. while streaming many large payloads to
+// a slow remote) as that prevents PostgreSQL from vacuuming rows deleted
+// or updated after the snapshot was taken.
+func (b *Backend) WithSnapshot(ctx context.Context, fn func(SnapshotView) error) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return fmt.Errorf("failed to begin snapshot transaction: %w", err)
+	}
+
+	return endTransaction(tx, fn(&snapshotView{backend: b, tx: tx}))
+}
+
+// endTransaction commits tx if err is nil, otherwise rolls it back. On
+// rollback the error reported to the caller is err, not a rollback
+// failure; on commit it is Commit's error, if any.
+func endTransaction(tx *sql.Tx, err error) error {
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type snapshotView struct {
+	backend *Backend
+	tx      *sql.Tx
+}
+
+func (v *snapshotView) GetDataReader(ctx context.Context, messageID string) (io.ReadCloser, error) {
+	query := fmt.Sprintf(`SELECT data FROM %s WHERE message_id = $1`, v.backend.tableName)
+
+	if v.backend.mode == ModeLargeObject {
+		var oid uint32
+		if err := v.tx.QueryRowContext(ctx, query, messageID).Scan(&oid); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("data for message %s not found", messageID)
+			}
+			return nil, fmt.Errorf("failed to get data for message %s: %w", messageID, err)
+		}
+
+		var fd int32
+		if err := v.tx.QueryRowContext(ctx, `SELECT lo_open($1, $2)`, oid, loModeRead).Scan(&fd); err != nil {
+			return nil, fmt.Errorf("failed to open large object %d for reading: %w", oid, err)
+		}
+		return &snapshotLOReader{ctx: ctx, tx: v.tx, fd: fd}, nil
+	}
+
+	var data []byte
+	if err := v.tx.QueryRowContext(ctx, query, messageID).Scan(&data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("data for message %s not found", messageID)
+		}
+		return nil, fmt.Errorf("failed to get data for message %s: %w", messageID, err)
+	}
+	return &bytesReadCloser{data: data}, nil
+}
+
+func (v *snapshotView) Size(ctx context.Context, messageID string) (int64, error) {
+	query := fmt.Sprintf(`SELECT size FROM %s WHERE message_id = $1`, v.backend.tableName)
+
+	var size int64
+	err := v.tx.QueryRowContext(ctx, query, messageID).Scan(&size)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("data for message %s not found", messageID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size for message %s: %w", messageID, err)
+	}
+	return size, nil
+}
+
+func (v *snapshotView) Exists(ctx context.Context, messageID string) (bool, error) {
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE message_id = $1)`, v.backend.tableName)
+
+	var exists bool
+	if err := v.tx.QueryRowContext(ctx, query, messageID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check existence for message %s: %w", messageID, err)
+	}
+	return exists, nil
+}
+
+func (v *snapshotView) Iterate(ctx context.Context, prefix string, since time.Time) (*SnapshotCursor, error) {
+	query := fmt.Sprintf(`
+		SELECT message_id, size, updated FROM %s
+		WHERE message_id LIKE $1 AND updated > $2
+		ORDER BY message_id
+	`, v.backend.tableName)
+
+	// Escape LIKE wildcards so prefix is matched literally.
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	rows, err := v.tx.QueryContext(ctx, query, escaped+"%", since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate messages: %w", err)
+	}
+	return &SnapshotCursor{rows: rows}, nil
+}
+
+// SnapshotCursor iterates over messages within a snapshot transaction,
+// following the standard Next/Err/Close shape of database/sql.Rows.
+type SnapshotCursor struct {
+	rows *sql.Rows
+
+	messageID string
+	size      int64
+	updated   time.Time
+	err       error
+}
+
+// Next advances the cursor, returning false when iteration is done or an
+// error occurred; check Err to distinguish the two.
+func (c *SnapshotCursor) Next() bool {
+	if !c.rows.Next() {
+		return false
+	}
+	if err := c.rows.Scan(&c.messageID, &c.size, &c.updated); err != nil {
+		c.err = err
+		return false
+	}
+	return true
+}
+
+// MessageID returns the current row's message ID.
+func (c *SnapshotCursor) MessageID() string { return c.messageID }
+
+// Size returns the current row's stored size.
+func (c *SnapshotCursor) Size() int64 { return c.size }
+
+// Updated returns the current row's last-updated timestamp.
+func (c *SnapshotCursor) Updated() time.Time { return c.updated }
+
+// Err returns the first error encountered while iterating, if any.
+func (c *SnapshotCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
+
+// Close releases the cursor's underlying rows.
+func (c *SnapshotCursor) Close() error {
+	return c.rows.Close()
+}
+
+// snapshotLOReader reads a large object within a snapshot transaction it
+// does not own; Close releases the large object descriptor but leaves the
+// transaction itself for WithSnapshot to commit or roll back.
+type snapshotLOReader struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	fd     int32
+	eof    bool
+	closed bool
+}
+
+func (r *snapshotLOReader) Read(p []byte) (int, error) {
+	if r.eof {
+		return 0, io.EOF
+	}
+
+	chunk, err := readLargeObjectChunk(r.ctx, r.tx, r.fd, len(p))
+	if err != nil {
+		return 0, err
+	}
+	if len(chunk) < minInt(len(p), loChunkSize) {
+		r.eof = true
+	}
+	if len(chunk) == 0 {
+		return 0, io.EOF
+	}
+
+	return copy(p, chunk), nil
+}
+
+func (r *snapshotLOReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	_, err := r.tx.ExecContext(r.ctx, `SELECT lo_close($1)`, r.fd)
+	return err
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackend_WithSnapshot(t *testing.T) {
+	backend := setupTestDB(t)
+	defer cleanupTestDB(t, backend)
+
+	ctx := context.Background()
+	messageID := "snapshot-message"
+	testData := "data visible inside the snapshot"
+
+	if _, err := backend.StoreData(ctx, messageID, strings.NewReader(testData)); err != nil {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+
+	err := backend.WithSnapshot(ctx, func(view SnapshotView) error {
+		exists, err := view.Exists(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			t.Error("Expected message to exist in snapshot")
+		}
+
+		size, err := view.Size(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if size != int64(len(testData)) {
+			t.Errorf("Size mismatch: expected %d, got %d", len(testData), size)
+		}
+
+		reader, err := view.GetDataReader(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if string(got) != testData {
+			t.Errorf("Data mismatch: expected %s, got %s", testData, string(got))
+		}
+
+		// A write made concurrently with the snapshot, through the backend
+		// rather than the snapshot's transaction, must not be visible inside it.
+		if _, err := backend.StoreData(ctx, "snapshot-concurrent-message", strings.NewReader("added after snapshot opened")); err != nil {
+			return err
+		}
+		visible, err := view.Exists(ctx, "snapshot-concurrent-message")
+		if err != nil {
+			return err
+		}
+		if visible {
+			t.Error("Expected concurrently-inserted message not to be visible inside the snapshot")
+		}
+
+		cursor, err := view.Iterate(ctx, "snapshot-", time.Time{})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		seen := map[string]bool{}
+		for cursor.Next() {
+			seen[cursor.MessageID()] = true
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		if !seen[messageID] {
+			t.Errorf("Expected Iterate to include %s", messageID)
+		}
+		if seen["snapshot-concurrent-message"] {
+			t.Error("Expected Iterate not to include the concurrently-inserted message")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithSnapshot failed: %v", err)
+	}
+
+	if err := backend.DeleteData(ctx, "snapshot-concurrent-message"); err != nil {
+		t.Fatalf("Failed to clean up concurrent message: %v", err)
+	}
+}